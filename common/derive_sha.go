@@ -0,0 +1,167 @@
+// Copyright (c) 2016, Alan Chen
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its contributors
+//    may be used to endorse or promote products derived from this software
+//    without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package common
+
+import (
+	"bytes"
+	"fmt"
+	"math/big"
+
+	"github.com/alanchchen/web3go/rlp"
+	"github.com/alanchchen/web3go/trie"
+)
+
+// DerivableList is the interface implemented by ordered lists that
+// DeriveSha can compute a Merkle root for.
+type DerivableList interface {
+	Len() int
+	EncodeIndex(i int, w *bytes.Buffer)
+}
+
+// DeriveSha builds the ephemeral Merkle-Patricia trie used for
+// transactionsRoot, receiptsRoot, and withdrawalsRoot: for index i the key
+// is rlp.Encode(uint(i)) and the value is list.EncodeIndex(i), and the
+// returned hash is the keccak256 of the RLP of the trie's root node.
+func DeriveSha(list DerivableList) Hash {
+	t := trie.NewStackTrie(nil)
+	buf := new(bytes.Buffer)
+	for i := 0; i < list.Len(); i++ {
+		buf.Reset()
+		rlp.Encode(buf, uint(i))
+		key := CopyBytes(buf.Bytes())
+
+		buf.Reset()
+		list.EncodeIndex(i, buf)
+		t.Update(key, CopyBytes(buf.Bytes()))
+	}
+	root := t.Hash()
+	return NewHash(root[:])
+}
+
+// TransactionsList implements DerivableList over a slice of transactions,
+// for computing Block.TransactionRoot.
+type TransactionsList []*Transactions
+
+func (s TransactionsList) Len() int { return len(s) }
+
+// EncodeIndex writes the canonical encoding of transaction i: the
+// EIP-2718 envelope bytes for typed transactions, or plain RLP for legacy
+// ones. This is the same encoding produced by MarshalBinary, not the
+// RLP-wrapped form used when a typed transaction is embedded in a list.
+func (s TransactionsList) EncodeIndex(i int, w *bytes.Buffer) {
+	raw, err := s[i].MarshalBinary()
+	if err != nil {
+		panic(err)
+	}
+	w.Write(raw)
+}
+
+// ReceiptsList implements DerivableList over a slice of receipts, for
+// computing a block's receipts root.
+type ReceiptsList []*TransactionReceipt
+
+func (r ReceiptsList) Len() int { return len(r) }
+
+// consensusReceiptRLP is the real consensus encoding of a receipt:
+// [status, cumulativeGasUsed, bloom, logs]. The first element is always
+// the receipt's Status; the pre-Byzantium form, which carries an
+// intermediate state root there instead, is not encoded. TransactionReceipt
+// itself carries a number of RPC-only fields (Hash, BlockNumber,
+// ContractAddress, From, ...) that have no place in this encoding, so it
+// is built from TransactionReceipt rather than encoding it directly.
+type consensusReceiptRLP struct {
+	Status            uint64
+	CumulativeGasUsed *big.Int
+	Bloom             Hash
+	Logs              []*consensusLogRLP
+}
+
+// consensusLogRLP is the consensus encoding of a log: [address, topics, data].
+type consensusLogRLP struct {
+	Address Address
+	Topics  []Hash
+	Data    []byte
+}
+
+func consensusLogsRLP(logs []Log) []*consensusLogRLP {
+	out := make([]*consensusLogRLP, len(logs))
+	for i, l := range logs {
+		topics := make([]Hash, len(l.Topics))
+		for j, t := range l.Topics {
+			topics[j] = NewHash(t.Data)
+		}
+		out[i] = &consensusLogRLP{Address: l.Address, Topics: topics, Data: l.Data}
+	}
+	return out
+}
+
+// EncodeIndex writes the canonical encoding of receipt i: a leading type
+// byte for typed-transaction receipts, followed by the RLP of the
+// consensus-shaped receipt, mirroring EncodeIndex on TransactionsList.
+func (r ReceiptsList) EncodeIndex(i int, w *bytes.Buffer) {
+	receipt := r[i]
+	if receipt.Type != uint8(LegacyTxType) {
+		w.WriteByte(receipt.Type)
+	}
+	enc := &consensusReceiptRLP{
+		Status:            receipt.Status,
+		CumulativeGasUsed: receipt.CumulativeGasUsed,
+		Bloom:             receipt.Bloom,
+		Logs:              consensusLogsRLP(receipt.Logs),
+	}
+	if err := rlp.Encode(w, enc); err != nil {
+		panic(err)
+	}
+}
+
+// UncleHashes implements DerivableList over a block's uncle hashes.
+type UncleHashes []Hash
+
+func (u UncleHashes) Len() int { return len(u) }
+
+func (u UncleHashes) EncodeIndex(i int, w *bytes.Buffer) {
+	if err := rlp.Encode(w, u[i]); err != nil {
+		panic(err)
+	}
+}
+
+// VerifyBlockRoots checks that txs and receipts hash, via DeriveSha, to the
+// transactionsRoot and receiptsRoot recorded in b. This lets a light
+// client using this module prove inclusion of a transaction or receipt
+// against a header without running a full node.
+func VerifyBlockRoots(b *Block, txs []*Transactions, receipts []*TransactionReceipt) error {
+	if got, want := DeriveSha(TransactionsList(txs)), b.TransactionRoot; got != want {
+		return fmt.Errorf("transactions root mismatch: have %s, want %s", got.String(), want.String())
+	}
+	if got, want := DeriveSha(ReceiptsList(receipts)), b.ReceiptsRoot; got != want {
+		return fmt.Errorf("receipts root mismatch: have %s, want %s", got.String(), want.String())
+	}
+	return nil
+}
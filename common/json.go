@@ -0,0 +1,317 @@
+// Copyright (c) 2016, Alan Chen
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its contributors
+//    may be used to endorse or promote products derived from this software
+//    without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+//
+// This file hand-writes the gencodec-style MarshalJSON/UnmarshalJSON pairs
+// that the eth JSON-RPC wire format needs: *big.Int as 0x-prefixed hex
+// rather than decimal, []byte as 0x-prefixed hex rather than base64, and
+// Hash/Address as 0x-prefixed hex rather than a JSON byte array.
+
+package common
+
+import (
+	"encoding/json"
+	"math/big"
+
+	"github.com/alanchchen/web3go/hexutil"
+)
+
+// MarshalText implements encoding.TextMarshaler.
+func (hash Hash) MarshalText() ([]byte, error) {
+	return hexutil.Bytes(hash[:]).MarshalText()
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (hash *Hash) UnmarshalText(input []byte) error {
+	return hexutil.UnmarshalFixedText("Hash", input, hash[:])
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (addr Address) MarshalText() ([]byte, error) {
+	return hexutil.Bytes(addr[:]).MarshalText()
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (addr *Address) UnmarshalText(input []byte) error {
+	return hexutil.UnmarshalFixedText("Address", input, addr[:])
+}
+
+// MarshalText implements encoding.TextMarshaler. Topics are always
+// 32-byte values, so they're encoded the same way a Hash is.
+func (t Topic) MarshalText() ([]byte, error) {
+	return hexutil.Bytes(t.Data).MarshalText()
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (t *Topic) UnmarshalText(input []byte) error {
+	var h Hash
+	if err := h.UnmarshalText(input); err != nil {
+		return err
+	}
+	t.Data = CopyBytes(h[:])
+	return nil
+}
+
+// transactionJSON is the RPC representation of a Transaction.
+type transactionJSON struct {
+	Hash             Hash           `json:"hash"`
+	Nonce            Hash           `json:"nonce"`
+	BlockHash        Hash           `json:"blockHash"`
+	BlockNumber      *hexutil.Big   `json:"blockNumber"`
+	TransactionIndex hexutil.Uint64 `json:"transactionIndex"`
+	From             Address        `json:"from"`
+	To               Address        `json:"to"`
+	Gas              *hexutil.Big   `json:"gas"`
+	GasPrice         *hexutil.Big   `json:"gasprice"`
+	Value            *hexutil.Big   `json:"value"`
+	Data             hexutil.Bytes  `json:"input"`
+}
+
+// MarshalJSON implements json.Marshaler.
+func (tx *Transaction) MarshalJSON() ([]byte, error) {
+	return json.Marshal(&transactionJSON{
+		Hash:             tx.Hash,
+		Nonce:            tx.Nonce,
+		BlockHash:        tx.BlockHash,
+		BlockNumber:      (*hexutil.Big)(tx.BlockNumber),
+		TransactionIndex: hexutil.Uint64(tx.TransactionIndex),
+		From:             tx.From,
+		To:               tx.To,
+		Gas:              (*hexutil.Big)(tx.Gas),
+		GasPrice:         (*hexutil.Big)(tx.GasPrice),
+		Value:            (*hexutil.Big)(tx.Value),
+		Data:             hexutil.Bytes(tx.Data),
+	})
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (tx *Transaction) UnmarshalJSON(input []byte) error {
+	var dec transactionJSON
+	if err := json.Unmarshal(input, &dec); err != nil {
+		return err
+	}
+	tx.Hash = dec.Hash
+	tx.Nonce = dec.Nonce
+	tx.BlockHash = dec.BlockHash
+	tx.BlockNumber = (*big.Int)(dec.BlockNumber)
+	tx.TransactionIndex = uint64(dec.TransactionIndex)
+	tx.From = dec.From
+	tx.To = dec.To
+	tx.Gas = (*big.Int)(dec.Gas)
+	tx.GasPrice = (*big.Int)(dec.GasPrice)
+	tx.Value = (*big.Int)(dec.Value)
+	tx.Data = []byte(dec.Data)
+	return nil
+}
+
+// logJSON is the RPC representation of a Log.
+type logJSON struct {
+	LogIndex         hexutil.Uint64 `json:"logIndex"`
+	BlockNumber      *hexutil.Big   `json:"blockNumber"`
+	BlockHash        Hash           `json:"blockHash"`
+	TransactionHash  Hash           `json:"transactionHash"`
+	TransactionIndex hexutil.Uint64 `json:"transactionIndex"`
+	Address          Address        `json:"address"`
+	Data             hexutil.Bytes  `json:"data"`
+	Topics           Topics         `json:"topics"`
+}
+
+// MarshalJSON implements json.Marshaler.
+func (log *Log) MarshalJSON() ([]byte, error) {
+	return json.Marshal(&logJSON{
+		LogIndex:         hexutil.Uint64(log.LogIndex),
+		BlockNumber:      (*hexutil.Big)(log.BlockNumber),
+		BlockHash:        log.BlockHash,
+		TransactionHash:  log.TransactionHash,
+		TransactionIndex: hexutil.Uint64(log.TransactionIndex),
+		Address:          log.Address,
+		Data:             hexutil.Bytes(log.Data),
+		Topics:           log.Topics,
+	})
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (log *Log) UnmarshalJSON(input []byte) error {
+	var dec logJSON
+	if err := json.Unmarshal(input, &dec); err != nil {
+		return err
+	}
+	log.LogIndex = uint64(dec.LogIndex)
+	log.BlockNumber = (*big.Int)(dec.BlockNumber)
+	log.BlockHash = dec.BlockHash
+	log.TransactionHash = dec.TransactionHash
+	log.TransactionIndex = uint64(dec.TransactionIndex)
+	log.Address = dec.Address
+	log.Data = []byte(dec.Data)
+	log.Topics = dec.Topics
+	return nil
+}
+
+// transactionReceiptJSON is the RPC representation of a TransactionReceipt.
+type transactionReceiptJSON struct {
+	Hash              Hash           `json:"transactionHash"`
+	TransactionIndex  hexutil.Uint64 `json:"transactionIndex"`
+	BlockNumber       *hexutil.Big   `json:"blockNumber"`
+	BlockHash         Hash           `json:"blockHash"`
+	CumulativeGasUsed *hexutil.Big   `json:"cumulativeGasUsed"`
+	GasUsed           *hexutil.Big   `json:"gasUsed"`
+	ContractAddress   Address        `json:"contractAddress"`
+	Logs              []Log          `json:"logs"`
+	Status            hexutil.Uint64 `json:"status"`
+	EffectiveGasPrice *hexutil.Big   `json:"effectiveGasPrice"`
+	Type              hexutil.Uint64 `json:"type"`
+	From              Address        `json:"from"`
+	Bloom             Hash           `json:"logsBloom"`
+}
+
+// MarshalJSON implements json.Marshaler.
+func (r *TransactionReceipt) MarshalJSON() ([]byte, error) {
+	return json.Marshal(&transactionReceiptJSON{
+		Hash:              r.Hash,
+		TransactionIndex:  hexutil.Uint64(r.TransactionIndex),
+		BlockNumber:       (*hexutil.Big)(r.BlockNumber),
+		BlockHash:         r.BlockHash,
+		CumulativeGasUsed: (*hexutil.Big)(r.CumulativeGasUsed),
+		GasUsed:           (*hexutil.Big)(r.GasUsed),
+		ContractAddress:   r.ContractAddress,
+		Logs:              r.Logs,
+		Status:            hexutil.Uint64(r.Status),
+		EffectiveGasPrice: (*hexutil.Big)(r.EffectiveGasPrice),
+		Type:              hexutil.Uint64(r.Type),
+		From:              r.From,
+		Bloom:             r.Bloom,
+	})
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (r *TransactionReceipt) UnmarshalJSON(input []byte) error {
+	var dec transactionReceiptJSON
+	if err := json.Unmarshal(input, &dec); err != nil {
+		return err
+	}
+	r.Hash = dec.Hash
+	r.TransactionIndex = uint64(dec.TransactionIndex)
+	r.BlockNumber = (*big.Int)(dec.BlockNumber)
+	r.BlockHash = dec.BlockHash
+	r.CumulativeGasUsed = (*big.Int)(dec.CumulativeGasUsed)
+	r.GasUsed = (*big.Int)(dec.GasUsed)
+	r.ContractAddress = dec.ContractAddress
+	r.Logs = dec.Logs
+	r.Status = uint64(dec.Status)
+	r.EffectiveGasPrice = (*big.Int)(dec.EffectiveGasPrice)
+	r.Type = uint8(dec.Type)
+	r.From = dec.From
+	r.Bloom = dec.Bloom
+	return nil
+}
+
+// blockJSON is the RPC representation of a Block.
+type blockJSON struct {
+	Number          *hexutil.Big `json:"number"`
+	Hash            Hash         `json:"hash"`
+	ParentHash      Hash         `json:"parentHash"`
+	Nonce           Hash         `json:"nonce"`
+	Sha3Uncles      Hash         `json:"sha3Uncles"`
+	Bloom           Hash         `json:"logsBloom"`
+	TransactionRoot Hash         `json:"transactionsRoot"`
+	StateRoot       Hash         `json:"stateRoot"`
+	ReceiptsRoot    Hash         `json:"receiptsRoot"`
+	Miner           Address      `json:"miner"`
+	Difficulty      *hexutil.Big `json:"difficulty"`
+	TotalDifficulty *hexutil.Big `json:"totalDifficulty"`
+	ExtraData       Hash         `json:"extraData"`
+	Size            *hexutil.Big `json:"size"`
+	GasLimit        *hexutil.Big `json:"gasLimit"`
+	GasUsed         *hexutil.Big `json:"gasUsed"`
+	Timestamp       *hexutil.Big `json:"timestamp"`
+	Transactions    []Hash       `json:"transactions"`
+	Uncles          []Hash       `json:"uncles"`
+
+	BaseFee       *hexutil.Big    `json:"baseFeePerGas,omitempty"`
+	BlobGasUsed   *hexutil.Uint64 `json:"blobGasUsed,omitempty"`
+	ExcessBlobGas *hexutil.Uint64 `json:"excessBlobGas,omitempty"`
+}
+
+// MarshalJSON implements json.Marshaler.
+func (b *Block) MarshalJSON() ([]byte, error) {
+	return json.Marshal(&blockJSON{
+		Number:          (*hexutil.Big)(b.Number),
+		Hash:            b.Hash,
+		ParentHash:      b.ParentHash,
+		Nonce:           b.Nonce,
+		Sha3Uncles:      b.Sha3Uncles,
+		Bloom:           b.Bloom,
+		TransactionRoot: b.TransactionRoot,
+		StateRoot:       b.StateRoot,
+		ReceiptsRoot:    b.ReceiptsRoot,
+		Miner:           b.Miner,
+		Difficulty:      (*hexutil.Big)(b.Difficulty),
+		TotalDifficulty: (*hexutil.Big)(b.TotalDifficulty),
+		ExtraData:       b.ExtraData,
+		Size:            (*hexutil.Big)(b.Size),
+		GasLimit:        (*hexutil.Big)(b.GasLimit),
+		GasUsed:         (*hexutil.Big)(b.GasUsed),
+		Timestamp:       (*hexutil.Big)(b.Timestamp),
+		Transactions:    b.Transactions,
+		Uncles:          b.Uncles,
+		BaseFee:         (*hexutil.Big)(b.BaseFee),
+		BlobGasUsed:     (*hexutil.Uint64)(b.BlobGasUsed),
+		ExcessBlobGas:   (*hexutil.Uint64)(b.ExcessBlobGas),
+	})
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (b *Block) UnmarshalJSON(input []byte) error {
+	var dec blockJSON
+	if err := json.Unmarshal(input, &dec); err != nil {
+		return err
+	}
+	b.Number = (*big.Int)(dec.Number)
+	b.Hash = dec.Hash
+	b.ParentHash = dec.ParentHash
+	b.Nonce = dec.Nonce
+	b.Sha3Uncles = dec.Sha3Uncles
+	b.Bloom = dec.Bloom
+	b.TransactionRoot = dec.TransactionRoot
+	b.StateRoot = dec.StateRoot
+	b.ReceiptsRoot = dec.ReceiptsRoot
+	b.Miner = dec.Miner
+	b.Difficulty = (*big.Int)(dec.Difficulty)
+	b.TotalDifficulty = (*big.Int)(dec.TotalDifficulty)
+	b.ExtraData = dec.ExtraData
+	b.Size = (*big.Int)(dec.Size)
+	b.GasLimit = (*big.Int)(dec.GasLimit)
+	b.GasUsed = (*big.Int)(dec.GasUsed)
+	b.Timestamp = (*big.Int)(dec.Timestamp)
+	b.Transactions = dec.Transactions
+	b.Uncles = dec.Uncles
+	b.BaseFee = (*big.Int)(dec.BaseFee)
+	b.BlobGasUsed = (*uint64)(dec.BlobGasUsed)
+	b.ExcessBlobGas = (*uint64)(dec.ExcessBlobGas)
+	return nil
+}
@@ -0,0 +1,171 @@
+// Copyright (c) 2016, Alan Chen
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its contributors
+//    may be used to endorse or promote products derived from this software
+//    without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package common
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+// The fixtures below are synthesized to match this module's field shapes,
+// not captured verbatim, so that round-tripping them still exercises the
+// same hex encodings a live node's JSON-RPC responses use. One shape
+// mismatch worth calling out: Transaction.Nonce is typed as a 32-byte
+// Hash rather than a numeric quantity, so unlike a real node's small
+// "0x21"-style nonce, txFixture's nonce below is padded out to 32 bytes to
+// satisfy that type. That's a pre-existing field-typing issue, not
+// something these tests paper over.
+
+const txFixture = `{
+	"hash": "0xbb3a336e3f823ec18197f1e13ee875700f08f03e2cab75f0d0b118dabb44cba1",
+	"nonce": "0x0000000000000000000000000000000000000000000000000000000000000021",
+	"blockHash": "0x1d59ff54b1eb26b013ce3cb5fc9dab3705b415a67127a003c3e61eb445bb8df1",
+	"blockNumber": "0x5daf3b",
+	"transactionIndex": "0x41",
+	"from": "0xa7d9ddbe1f17865597fbd27ec712455208b6b76d",
+	"to": "0xf02c1c8e6114b1dbe8937a39260b5b0a374432bb",
+	"gas": "0xc350",
+	"gasprice": "0x4a817c800",
+	"value": "0xf3dbb76162000",
+	"input": "0x68656c6c6f21"
+}`
+
+const logFixture = `{
+	"logIndex": "0x1",
+	"blockNumber": "0x1b4",
+	"blockHash": "0x8216c5785ac562ff41e2dcfdf5785ac562ff41e2dcfdf829c5a142f1fcca5d61",
+	"transactionHash": "0xdf829c5a142f1fcca5d6fc4af674513ee2fa7ae670fd086e0f5271b5edaee5f1",
+	"transactionIndex": "0x0",
+	"address": "0x16c5785ac562ff41e2dcfdf5785ac562ff41e2dc",
+	"data": "0x00000000000000000000000000000000000000000000000000000000000000",
+	"topics": [
+		"0x59ebeb90bc63057b6515673c3ecf9438e5058bca0f92585014eced636878c9a1",
+		"0x0000000000000000000000000000000000000000000000000000000000000001"
+	]
+}`
+
+const receiptFixture = `{
+	"transactionHash": "0xbb3a336e3f823ec18197f1e13ee875700f08f03e2cab75f0d0b118dabb44cba1",
+	"transactionIndex": "0x41",
+	"blockNumber": "0x5daf3b",
+	"blockHash": "0x1d59ff54b1eb26b013ce3cb5fc9dab3705b415a67127a003c3e61eb445bb8df1",
+	"cumulativeGasUsed": "0x33bc",
+	"gasUsed": "0x4dc",
+	"contractAddress": "0x0000000000000000000000000000000000000000",
+	"logs": [],
+	"logsBloom": "0x0000000000000000000000000000000000000000000000000000000000000001",
+	"status": "0x1",
+	"effectiveGasPrice": "0x4a817c800",
+	"type": "0x2",
+	"from": "0xa7d9ddbe1f17865597fbd27ec712455208b6b76d"
+}`
+
+// TestTransactionJSONRoundTrip checks that an eth_getTransactionByHash-shaped
+// fixture survives Unmarshal -> Marshal -> Unmarshal with no loss of data.
+func TestTransactionJSONRoundTrip(t *testing.T) {
+	var tx Transaction
+	if err := json.Unmarshal([]byte(txFixture), &tx); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if tx.TransactionIndex != 0x41 {
+		t.Errorf("transactionIndex = %#x, want 0x41", tx.TransactionIndex)
+	}
+	if tx.Gas.Uint64() != 0xc350 {
+		t.Errorf("gas = %#x, want 0xc350", tx.Gas.Uint64())
+	}
+
+	out, err := json.Marshal(&tx)
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	var roundTripped Transaction
+	if err := json.Unmarshal(out, &roundTripped); err != nil {
+		t.Fatalf("unmarshal round-tripped json: %v", err)
+	}
+	if roundTripped.Hash != tx.Hash || roundTripped.Gas.Cmp(tx.Gas) != 0 {
+		t.Errorf("round trip mismatch: got %+v, want %+v", roundTripped, tx)
+	}
+}
+
+// TestLogJSONTopicsHexEncoding checks that Topics marshal back to a plain
+// array of 0x-prefixed hex strings, matching what eth_getLogs sends, rather
+// than Topic's underlying struct representation.
+func TestLogJSONTopicsHexEncoding(t *testing.T) {
+	var log Log
+	if err := json.Unmarshal([]byte(logFixture), &log); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if len(log.Topics) != 2 {
+		t.Fatalf("len(Topics) = %d, want 2", len(log.Topics))
+	}
+
+	out, err := json.Marshal(&log)
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+
+	var decoded struct {
+		Topics []string `json:"topics"`
+	}
+	if err := json.Unmarshal(out, &decoded); err != nil {
+		t.Fatalf("topics did not marshal as a plain hex string array: %v", err)
+	}
+	if len(decoded.Topics) != 2 || decoded.Topics[0][:2] != "0x" {
+		t.Errorf("topics = %v, want 0x-prefixed hex strings", decoded.Topics)
+	}
+}
+
+// TestTransactionReceiptJSONRoundTrip checks that an
+// eth_getTransactionReceipt-shaped fixture, including the post-EIP-1559 Status,
+// EffectiveGasPrice, Type, From, and Bloom fields, round-trips cleanly.
+func TestTransactionReceiptJSONRoundTrip(t *testing.T) {
+	var r TransactionReceipt
+	if err := json.Unmarshal([]byte(receiptFixture), &r); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if r.Status != 1 {
+		t.Errorf("status = %d, want 1", r.Status)
+	}
+	if r.Type != 2 {
+		t.Errorf("type = %d, want 2", r.Type)
+	}
+
+	out, err := json.Marshal(&r)
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	var roundTripped TransactionReceipt
+	if err := json.Unmarshal(out, &roundTripped); err != nil {
+		t.Fatalf("unmarshal round-tripped json: %v", err)
+	}
+	if roundTripped.Status != r.Status || roundTripped.Type != r.Type || roundTripped.Bloom != r.Bloom {
+		t.Errorf("round trip mismatch: got %+v, want %+v", roundTripped, r)
+	}
+}
@@ -0,0 +1,438 @@
+// Copyright (c) 2016, Alan Chen
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its contributors
+//    may be used to endorse or promote products derived from this software
+//    without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package common
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/alanchchen/web3go/crypto"
+	"github.com/alanchchen/web3go/rlp"
+)
+
+var (
+	ErrInvalidChainID = errors.New("invalid chain id for signer")
+	ErrInvalidSig     = errors.New("invalid transaction v, r, s values")
+)
+
+var big8 = big.NewInt(8)
+
+// sigCache is used to cache the derived sender and the signer used to
+// derive it on a Transactions value, so repeated Sender calls with the
+// same signer are free.
+type sigCache struct {
+	signer Signer
+	from   Address
+}
+
+// Signer encapsulates transaction signature handling. The name of this
+// type is slightly misleading because Signers don't actually sign, they're
+// used to recover a sender from a signed transaction and to compute the
+// hash that gets signed, and to set the signature fields once a raw
+// signature has been produced.
+//
+// Note that this interface is not a stable API and may change at any time
+// to accommodate new protocol rules.
+type Signer interface {
+	// Sender returns the sender address of the transaction.
+	Sender(tx *Transactions) (Address, error)
+	// SignatureValues returns the raw R, S, V values corresponding to the
+	// given signature.
+	SignatureValues(tx *Transactions, sig []byte) (r, s, v *big.Int, err error)
+	// Hash returns the hash to be signed.
+	Hash(tx *Transactions) Hash
+	// Equal reports whether the two signers are equivalent.
+	Equal(Signer) bool
+}
+
+// SignTx signs the transaction using the given signer and private key.
+func SignTx(tx *Transactions, s Signer, prv *ecdsa.PrivateKey) (*Transactions, error) {
+	h := s.Hash(tx)
+	sig, err := crypto.Sign(h[:], prv)
+	if err != nil {
+		return nil, err
+	}
+	return tx.WithSignature(s, sig)
+}
+
+// WithSignature returns a copy of tx with the given signature applied,
+// interpreted according to the given signer.
+func (tx *Transactions) WithSignature(signer Signer, sig []byte) (*Transactions, error) {
+	r, s, v, err := signer.SignatureValues(tx, sig)
+	if err != nil {
+		return nil, err
+	}
+	cpy := &Transactions{data: tx.data, time: tx.time}
+	cpy.data.R, cpy.data.S, cpy.data.V = r, s, v
+	return cpy, nil
+}
+
+// Sender returns the address derived from the signature of tx, caching the
+// result so repeated calls with an equivalent signer are free.
+func Sender(signer Signer, tx *Transactions) (Address, error) {
+	if cached := tx.from.Load(); cached != nil {
+		cache := cached.(sigCache)
+		if cache.signer.Equal(signer) {
+			return cache.from, nil
+		}
+	}
+
+	addr, err := signer.Sender(tx)
+	if err != nil {
+		return Address{}, err
+	}
+	tx.from.Store(sigCache{signer: signer, from: addr})
+	return addr, nil
+}
+
+// LatestSignerForChainID returns the most permissive signer that accepts
+// all the transaction types this module knows how to handle for the given
+// chain ID. Use this in cases where the chain id is known and a specific
+// fork (and thus a narrower signer) isn't required.
+//
+// Upstream also offers a LatestSigner(chainConfig) that picks the signer
+// by fork rules for a given block, rather than just by chain id. This
+// module has no ChainConfig type — it doesn't track fork activation
+// blocks, only the wire formats a node already on the latest fork
+// produces — so there is no narrower-than-"latest" signer for it to
+// select between; LatestSignerForChainID is the only factory this module
+// needs.
+func LatestSignerForChainID(chainID *big.Int) Signer {
+	return NewEIP1559Signer(chainID)
+}
+
+// isProtectedV reports whether V encodes an EIP-155 chain id, i.e. whether
+// the transaction is replay-protected.
+func isProtectedV(V *big.Int) bool {
+	if V.BitLen() <= 8 {
+		v := V.Uint64()
+		return v != 27 && v != 28
+	}
+	// anything not 27 or 28 is considered protected
+	return true
+}
+
+// protected reports whether tx carries EIP-155 replay protection.
+func (tx *Transactions) protected() bool {
+	return tx.data.Type != LegacyTxType || isProtectedV(tx.data.V)
+}
+
+// chainID returns the chain id encoded in an EIP-155 protected legacy
+// transaction's V value.
+func (tx *Transactions) chainID() *big.Int {
+	v := new(big.Int).Sub(tx.data.V, big.NewInt(35))
+	return v.Div(v, big.NewInt(2))
+}
+
+// decodeSignature parses the 65-byte [R || S || V] signature produced by
+// crypto.Sign into its big.Int components, with V left in its raw [0, 1]
+// form; callers adjust V to match the encoding their signer expects.
+func decodeSignature(sig []byte) (r, s, v *big.Int, err error) {
+	if len(sig) != 65 {
+		return nil, nil, nil, fmt.Errorf("wrong size for signature: got %d, want 65", len(sig))
+	}
+	r = new(big.Int).SetBytes(sig[:32])
+	s = new(big.Int).SetBytes(sig[32:64])
+	v = new(big.Int).SetBytes([]byte{sig[64]})
+	return r, s, v, nil
+}
+
+// recoverPlain recovers the sender address from a signature over sighash.
+func recoverPlain(sighash Hash, R, S, Vb *big.Int, homestead bool) (Address, error) {
+	if Vb.BitLen() > 8 {
+		return Address{}, ErrInvalidSig
+	}
+	V := byte(Vb.Uint64() - 27)
+	if !crypto.ValidateSignatureValues(V, R, S, homestead) {
+		return Address{}, ErrInvalidSig
+	}
+	sig := make([]byte, 65)
+	rBytes, sBytes := R.Bytes(), S.Bytes()
+	copy(sig[32-len(rBytes):32], rBytes)
+	copy(sig[64-len(sBytes):64], sBytes)
+	sig[64] = V
+	pub, err := crypto.Ecrecover(sighash[:], sig)
+	if err != nil {
+		return Address{}, err
+	}
+	if len(pub) == 0 || pub[0] != 4 {
+		return Address{}, errors.New("invalid public key")
+	}
+	var addr Address
+	copy(addr[:], crypto.Keccak256(pub[1:])[12:])
+	return addr, nil
+}
+
+// rlpHash returns the keccak256 hash of the RLP encoding of x.
+func rlpHash(x interface{}) (h Hash) {
+	b, err := rlp.EncodeToBytes(x)
+	if err != nil {
+		panic(err)
+	}
+	copy(h[:], crypto.Keccak256(b))
+	return h
+}
+
+// prefixedRlpHash returns the keccak256 hash of prefix followed by the RLP
+// encoding of x, which is how EIP-2718 typed transactions are hashed.
+func prefixedRlpHash(prefix byte, x interface{}) (h Hash) {
+	buf := new(bytes.Buffer)
+	buf.WriteByte(prefix)
+	if err := rlp.Encode(buf, x); err != nil {
+		panic(err)
+	}
+	copy(h[:], crypto.Keccak256(buf.Bytes()))
+	return h
+}
+
+// HomesteadSigner implements Signer for transactions without replay
+// protection, as used before EIP-155.
+type HomesteadSigner struct{}
+
+func (s HomesteadSigner) Equal(s2 Signer) bool {
+	_, ok := s2.(HomesteadSigner)
+	return ok
+}
+
+func (s HomesteadSigner) Hash(tx *Transactions) Hash {
+	return rlpHash([]interface{}{
+		tx.data.AccountNonce,
+		tx.data.Price,
+		tx.data.GasLimit,
+		tx.data.Recipient,
+		tx.data.Amount,
+		tx.data.Payload,
+	})
+}
+
+func (s HomesteadSigner) SignatureValues(tx *Transactions, sig []byte) (r, sVal, v *big.Int, err error) {
+	r, sVal, v, err = decodeSignature(sig)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	v.Add(v, big.NewInt(27))
+	return r, sVal, v, nil
+}
+
+func (s HomesteadSigner) Sender(tx *Transactions) (Address, error) {
+	if tx.data.Type != LegacyTxType {
+		return Address{}, ErrTxTypeNotSupported
+	}
+	return recoverPlain(s.Hash(tx), tx.data.R, tx.data.S, tx.data.V, true)
+}
+
+// EIP155Signer implements Signer using the EIP-155 replay protection rules
+// for legacy transactions.
+type EIP155Signer struct {
+	chainID, chainIDMul *big.Int
+}
+
+// NewEIP155Signer creates an EIP155Signer for the given chain id. A nil or
+// zero chain id yields a signer that accepts unprotected transactions.
+func NewEIP155Signer(chainID *big.Int) EIP155Signer {
+	if chainID == nil {
+		chainID = new(big.Int)
+	}
+	return EIP155Signer{
+		chainID:    chainID,
+		chainIDMul: new(big.Int).Mul(chainID, big.NewInt(2)),
+	}
+}
+
+func (s EIP155Signer) Equal(s2 Signer) bool {
+	eip155, ok := s2.(EIP155Signer)
+	return ok && eip155.chainID.Cmp(s.chainID) == 0
+}
+
+func (s EIP155Signer) Hash(tx *Transactions) Hash {
+	if s.chainID.Sign() == 0 {
+		return HomesteadSigner{}.Hash(tx)
+	}
+	return rlpHash([]interface{}{
+		tx.data.AccountNonce,
+		tx.data.Price,
+		tx.data.GasLimit,
+		tx.data.Recipient,
+		tx.data.Amount,
+		tx.data.Payload,
+		s.chainID, uint(0), uint(0),
+	})
+}
+
+func (s EIP155Signer) Sender(tx *Transactions) (Address, error) {
+	if tx.data.Type != LegacyTxType {
+		return Address{}, ErrTxTypeNotSupported
+	}
+	if !tx.protected() {
+		return HomesteadSigner{}.Sender(tx)
+	}
+	if tx.chainID().Cmp(s.chainID) != 0 {
+		return Address{}, ErrInvalidChainID
+	}
+	V := new(big.Int).Sub(tx.data.V, s.chainIDMul)
+	V.Sub(V, big8)
+	return recoverPlain(s.Hash(tx), tx.data.R, tx.data.S, V, true)
+}
+
+func (s EIP155Signer) SignatureValues(tx *Transactions, sig []byte) (r, sVal, v *big.Int, err error) {
+	r, sVal, v, err = decodeSignature(sig)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if s.chainID.Sign() != 0 {
+		v = new(big.Int).Add(v, big.NewInt(35))
+		v.Add(v, s.chainIDMul)
+	} else {
+		v.Add(v, big.NewInt(27))
+	}
+	return r, sVal, v, nil
+}
+
+// EIP2930Signer implements Signer for EIP-2930 access-list transactions,
+// falling back to EIP155Signer for legacy ones.
+type EIP2930Signer struct {
+	EIP155Signer
+}
+
+// NewEIP2930Signer creates an EIP2930Signer for the given chain id.
+func NewEIP2930Signer(chainID *big.Int) EIP2930Signer {
+	return EIP2930Signer{NewEIP155Signer(chainID)}
+}
+
+func (s EIP2930Signer) Equal(s2 Signer) bool {
+	x, ok := s2.(EIP2930Signer)
+	return ok && x.chainID.Cmp(s.chainID) == 0
+}
+
+func (s EIP2930Signer) Hash(tx *Transactions) Hash {
+	if tx.data.Type == LegacyTxType {
+		return s.EIP155Signer.Hash(tx)
+	}
+	return prefixedRlpHash(byte(AccessListTxType), []interface{}{
+		s.chainID,
+		tx.data.AccountNonce,
+		tx.data.Price,
+		tx.data.GasLimit,
+		tx.data.Recipient,
+		tx.data.Amount,
+		tx.data.Payload,
+		tx.data.AccessList,
+	})
+}
+
+func (s EIP2930Signer) Sender(tx *Transactions) (Address, error) {
+	switch tx.data.Type {
+	case LegacyTxType:
+		return s.EIP155Signer.Sender(tx)
+	case AccessListTxType:
+		if tx.data.ChainID.Cmp(s.chainID) != 0 {
+			return Address{}, ErrInvalidChainID
+		}
+		V := new(big.Int).Add(tx.data.V, big.NewInt(27))
+		return recoverPlain(s.Hash(tx), tx.data.R, tx.data.S, V, true)
+	default:
+		return Address{}, ErrTxTypeNotSupported
+	}
+}
+
+func (s EIP2930Signer) SignatureValues(tx *Transactions, sig []byte) (r, sVal, v *big.Int, err error) {
+	switch tx.data.Type {
+	case LegacyTxType:
+		return s.EIP155Signer.SignatureValues(tx, sig)
+	case AccessListTxType:
+		r, sVal, _, err = decodeSignature(sig)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		v = new(big.Int).SetBytes([]byte{sig[64]})
+		return r, sVal, v, nil
+	default:
+		return nil, nil, nil, ErrTxTypeNotSupported
+	}
+}
+
+// EIP1559Signer implements Signer for EIP-1559 dynamic-fee transactions,
+// falling back to EIP2930Signer for access-list and legacy ones.
+type EIP1559Signer struct {
+	EIP2930Signer
+}
+
+// NewEIP1559Signer creates an EIP1559Signer for the given chain id.
+func NewEIP1559Signer(chainID *big.Int) EIP1559Signer {
+	return EIP1559Signer{NewEIP2930Signer(chainID)}
+}
+
+func (s EIP1559Signer) Equal(s2 Signer) bool {
+	x, ok := s2.(EIP1559Signer)
+	return ok && x.chainID.Cmp(s.chainID) == 0
+}
+
+func (s EIP1559Signer) Hash(tx *Transactions) Hash {
+	if tx.data.Type != DynamicFeeTxType {
+		return s.EIP2930Signer.Hash(tx)
+	}
+	return prefixedRlpHash(byte(DynamicFeeTxType), []interface{}{
+		s.chainID,
+		tx.data.AccountNonce,
+		tx.data.GasTipCap,
+		tx.data.GasFeeCap,
+		tx.data.GasLimit,
+		tx.data.Recipient,
+		tx.data.Amount,
+		tx.data.Payload,
+		tx.data.AccessList,
+	})
+}
+
+func (s EIP1559Signer) Sender(tx *Transactions) (Address, error) {
+	if tx.data.Type != DynamicFeeTxType {
+		return s.EIP2930Signer.Sender(tx)
+	}
+	if tx.data.ChainID.Cmp(s.chainID) != 0 {
+		return Address{}, ErrInvalidChainID
+	}
+	V := new(big.Int).Add(tx.data.V, big.NewInt(27))
+	return recoverPlain(s.Hash(tx), tx.data.R, tx.data.S, V, true)
+}
+
+func (s EIP1559Signer) SignatureValues(tx *Transactions, sig []byte) (r, sVal, v *big.Int, err error) {
+	if tx.data.Type != DynamicFeeTxType {
+		return s.EIP2930Signer.SignatureValues(tx, sig)
+	}
+	r, sVal, _, err = decodeSignature(sig)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	v = new(big.Int).SetBytes([]byte{sig[64]})
+	return r, sVal, v, nil
+}
@@ -0,0 +1,221 @@
+// Copyright (c) 2016, Alan Chen
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its contributors
+//    may be used to endorse or promote products derived from this software
+//    without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package common
+
+import (
+	"container/heap"
+	"errors"
+	"math/big"
+	"time"
+)
+
+// ErrGasFeeCapTooLow is returned by EffectiveGasTip when a dynamic-fee
+// transaction's fee cap is below the given base fee.
+var ErrGasFeeCapTooLow = errors.New("fee cap less than base fee")
+
+// Nonce returns the transaction's account nonce.
+func (tx *Transactions) Nonce() uint64 {
+	return tx.data.AccountNonce
+}
+
+// Time returns when this module first saw the transaction.
+func (tx *Transactions) Time() time.Time {
+	return tx.time
+}
+
+// GasPrice returns the legacy/access-list gas price. For dynamic-fee
+// transactions it returns the fee cap.
+func (tx *Transactions) GasPrice() *big.Int {
+	if tx.data.Type == DynamicFeeTxType {
+		return tx.data.GasFeeCap
+	}
+	return tx.data.Price
+}
+
+// GasTipCap returns the maximum tip per gas the sender is willing to pay
+// the miner, i.e. GasTipCap for dynamic-fee transactions or the plain gas
+// price otherwise.
+func (tx *Transactions) GasTipCap() *big.Int {
+	if tx.data.Type == DynamicFeeTxType {
+		return tx.data.GasTipCap
+	}
+	return tx.data.Price
+}
+
+// GasFeeCap returns the maximum fee per gas the sender is willing to pay,
+// i.e. GasFeeCap for dynamic-fee transactions or the plain gas price
+// otherwise.
+func (tx *Transactions) GasFeeCap() *big.Int {
+	if tx.data.Type == DynamicFeeTxType {
+		return tx.data.GasFeeCap
+	}
+	return tx.data.Price
+}
+
+// EffectiveGasTip returns the effective miner tip for this transaction
+// given a base fee: min(GasTipCap, GasFeeCap-baseFee). A nil baseFee is
+// treated as zero-cost inclusion, i.e. the full GasTipCap applies.
+func (tx *Transactions) EffectiveGasTip(baseFee *big.Int) (*big.Int, error) {
+	if baseFee == nil {
+		return tx.GasTipCap(), nil
+	}
+	feeCap := tx.GasFeeCap()
+	if feeCap.Cmp(baseFee) < 0 {
+		return nil, ErrGasFeeCapTooLow
+	}
+	tip := new(big.Int).Sub(feeCap, baseFee)
+	if gasTipCap := tx.GasTipCap(); tip.Cmp(gasTipCap) > 0 {
+		tip.Set(gasTipCap)
+	}
+	return tip, nil
+}
+
+// TxByNonce sorts a list of transactions by account nonce, ascending. This
+// is the order in which a single sender's transactions must be included.
+type TxByNonce []*Transactions
+
+func (s TxByNonce) Len() int           { return len(s) }
+func (s TxByNonce) Less(i, j int) bool { return s[i].data.AccountNonce < s[j].data.AccountNonce }
+func (s TxByNonce) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+
+// txWithMinerFee couples a transaction with its sender and its effective
+// miner tip at a fixed base fee, so that tip need only be computed once
+// per transaction rather than on every heap comparison.
+type txWithMinerFee struct {
+	tx   *Transactions
+	from Address
+	fee  *big.Int
+}
+
+func newTxWithMinerFee(tx *Transactions, from Address, baseFee *big.Int) (*txWithMinerFee, error) {
+	fee, err := tx.EffectiveGasTip(baseFee)
+	if err != nil {
+		return nil, err
+	}
+	return &txWithMinerFee{tx: tx, from: from, fee: fee}, nil
+}
+
+// TxByPriceAndTime sorts transactions by effective miner tip, highest
+// first, breaking ties by which transaction this module saw first. It
+// implements heap.Interface and backs TransactionsByPriceAndNonce.
+type TxByPriceAndTime []*txWithMinerFee
+
+func (s TxByPriceAndTime) Len() int { return len(s) }
+
+func (s TxByPriceAndTime) Less(i, j int) bool {
+	cmp := s[i].fee.Cmp(s[j].fee)
+	if cmp == 0 {
+		return s[i].tx.time.Before(s[j].tx.time)
+	}
+	return cmp > 0
+}
+
+func (s TxByPriceAndTime) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
+
+func (s *TxByPriceAndTime) Push(x interface{}) {
+	*s = append(*s, x.(*txWithMinerFee))
+}
+
+func (s *TxByPriceAndTime) Pop() interface{} {
+	old := *s
+	n := len(old)
+	item := old[n-1]
+	old[n-1] = nil
+	*s = old[:n-1]
+	return item
+}
+
+// TransactionsByPriceAndNonce is a heap-based iterator over a set of
+// transactions grouped by sender, yielding them in the order a miner
+// selecting for mempool inclusion would want: by decreasing effective gas
+// tip, while respecting each sender's nonce ordering.
+type TransactionsByPriceAndNonce struct {
+	txs     map[Address][]*Transactions
+	heads   TxByPriceAndTime
+	baseFee *big.Int
+}
+
+// NewTransactionsByPriceAndNonce creates a TransactionsByPriceAndNonce over
+// txs, a map from sender to that sender's transactions sorted by nonce.
+// Transactions whose fee cap is below baseFee are dropped. baseFee may be
+// nil to order purely by gas tip cap.
+func NewTransactionsByPriceAndNonce(txs map[Address][]*Transactions, baseFee *big.Int) *TransactionsByPriceAndNonce {
+	heads := make(TxByPriceAndTime, 0, len(txs))
+	for from, accTxs := range txs {
+		if len(accTxs) == 0 {
+			continue
+		}
+		wrapped, err := newTxWithMinerFee(accTxs[0], from, baseFee)
+		if err != nil {
+			delete(txs, from)
+			continue
+		}
+		heads = append(heads, wrapped)
+		txs[from] = accTxs[1:]
+	}
+	heap.Init(&heads)
+
+	return &TransactionsByPriceAndNonce{
+		txs:     txs,
+		heads:   heads,
+		baseFee: baseFee,
+	}
+}
+
+// Peek returns the next transaction by effective price, or nil if there
+// are none left.
+func (t *TransactionsByPriceAndNonce) Peek() *Transactions {
+	if len(t.heads) == 0 {
+		return nil
+	}
+	return t.heads[0].tx
+}
+
+// Shift replaces the current best-priced transaction with the next one
+// from the same sender.
+func (t *TransactionsByPriceAndNonce) Shift() {
+	if len(t.heads) == 0 {
+		return
+	}
+	acc := t.heads[0].from
+	if rest, ok := t.txs[acc]; ok && len(rest) > 0 {
+		if wrapped, err := newTxWithMinerFee(rest[0], acc, t.baseFee); err == nil {
+			t.heads[0], t.txs[acc] = wrapped, rest[1:]
+			heap.Fix(&t.heads, 0)
+			return
+		}
+	}
+	heap.Pop(&t.heads)
+}
+
+// Pop removes the best-priced transaction without replacing it.
+func (t *TransactionsByPriceAndNonce) Pop() {
+	heap.Pop(&t.heads)
+}
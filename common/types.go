@@ -30,11 +30,14 @@
 package common
 
 import (
+	"bytes"
 	"encoding/json"
-	"math/big"
-	"sync/atomic"
+	"errors"
 	"github.com/alanchchen/web3go/rlp"
 	"io"
+	"math/big"
+	"sync/atomic"
+	"time"
 )
 
 const (
@@ -137,6 +140,20 @@ type TransactionReceipt struct {
 	GasUsed           *big.Int `json:"gasUsed"`
 	ContractAddress   Address  `json:"contractAddress"`
 	Logs              []Log    `json:"logs"`
+
+	// Status is the post-Byzantium replacement for the pre-Byzantium
+	// intermediate state root: 1 for success, 0 for failure.
+	Status uint64 `json:"status"`
+	// EffectiveGasPrice is the actual gas price paid per unit of gas,
+	// taking the EIP-1559 base fee into account.
+	EffectiveGasPrice *big.Int `json:"effectiveGasPrice"`
+	// Type is the EIP-2718 envelope type of the transaction this receipt
+	// belongs to.
+	Type uint8 `json:"type"`
+	// From is the sender of the transaction this receipt belongs to.
+	From Address `json:"from"`
+	// Bloom is the bloom filter over this receipt's logs.
+	Bloom Hash `json:"logsBloom"`
 }
 
 func (tx *TransactionReceipt) String() string {
@@ -154,6 +171,7 @@ type Block struct {
 	Bloom           Hash     `json:"logsBloom"`
 	TransactionRoot Hash     `json:"transactionsRoot"`
 	StateRoot       Hash     `json:"stateRoot"`
+	ReceiptsRoot    Hash     `json:"receiptsRoot"`
 	Miner           Address  `json:"miner"`
 	Difficulty      *big.Int `json:"difficulty"`
 	TotalDifficulty *big.Int `json:"totalDifficulty"`
@@ -165,10 +183,53 @@ type Block struct {
 	Transactions    []Hash   `json:"transactions"`
 	Uncles          []Hash   `json:"uncles"`
 	//MinGasPrice     *big.Int `json:"minGasPrice"`
+
+	// BaseFee is the EIP-1559 base fee per gas. It is absent on blocks
+	// from before the London fork, so it is an optional trailing RLP
+	// element and an omitted JSON field rather than a required one.
+	BaseFee *big.Int `json:"baseFeePerGas,omitempty" rlp:"optional"`
+
+	// BlobGasUsed and ExcessBlobGas are EIP-4844 fields introduced by the
+	// Cancun fork. Like BaseFee, they are optional trailing elements so
+	// that blocks from before Cancun still decode.
+	BlobGasUsed   *uint64 `json:"blobGasUsed,omitempty" rlp:"optional"`
+	ExcessBlobGas *uint64 `json:"excessBlobGas,omitempty" rlp:"optional"`
 }
 
+// TxType distinguishes the EIP-2718 typed transaction envelopes this module
+// understands. The zero value is the original, untyped transaction.
+type TxType byte
+
+const (
+	LegacyTxType TxType = iota
+	AccessListTxType
+	DynamicFeeTxType
+)
+
+var (
+	// ErrTxTypeNotSupported is returned when a transaction type is not
+	// supported by the module in the current context.
+	ErrTxTypeNotSupported = errors.New("transaction type not supported")
+	// ErrEmptyTypedTx is returned when UnmarshalBinary is given a
+	// zero-length typed transaction envelope.
+	ErrEmptyTypedTx = errors.New("empty typed transaction bytes")
+)
+
+// AccessTuple is the element type of an EIP-2930 access list.
+type AccessTuple struct {
+	Address     Address `json:"address"     gencodec:"required"`
+	StorageKeys []Hash  `json:"storageKeys" gencodec:"required"`
+}
+
+// AccessList is an EIP-2930 access list.
+type AccessList []AccessTuple
+
 type Transactions struct {
 	data txdata
+	// time is when the module first saw this transaction locally; it is
+	// not part of the consensus encoding and exists only to break ties
+	// when ordering transactions for pool selection.
+	time time.Time
 	// caches
 	hash atomic.Value
 	size atomic.Value
@@ -176,12 +237,27 @@ type Transactions struct {
 }
 
 type txdata struct {
-	AccountNonce uint64          `json:"nonce"    gencodec:"required"`
-	Price        *big.Int        `json:"gasPrice" gencodec:"required"`
-	GasLimit     uint64          `json:"gas"      gencodec:"required"`
-	Recipient    *Address `json:"to"       rlp:"nil"` // nil means contract creation
-	Amount       *big.Int        `json:"value"    gencodec:"required"`
-	Payload      []byte          `json:"input"    gencodec:"required"`
+	// Type is the EIP-2718 envelope type. It is never RLP encoded as part
+	// of the payload itself; for typed transactions it is instead carried
+	// as the single leading byte of the envelope.
+	Type TxType `json:"type" rlp:"-"`
+
+	// ChainID and AccessList are only present on typed transactions
+	// (AccessListTxType and DynamicFeeTxType).
+	ChainID    *big.Int   `json:"chainId,omitempty" rlp:"-"`
+	AccessList AccessList `json:"accessList,omitempty" rlp:"-"`
+
+	AccountNonce uint64   `json:"nonce"    gencodec:"required"`
+	Price        *big.Int `json:"gasPrice" gencodec:"required"` // legacy and access-list gas price
+
+	// GasTipCap and GasFeeCap replace Price on DynamicFeeTxType.
+	GasTipCap *big.Int `json:"maxPriorityFeePerGas,omitempty" rlp:"-"`
+	GasFeeCap *big.Int `json:"maxFeePerGas,omitempty" rlp:"-"`
+
+	GasLimit  uint64   `json:"gas"      gencodec:"required"`
+	Recipient *Address `json:"to"       rlp:"nil"` // nil means contract creation
+	Amount    *big.Int `json:"value"    gencodec:"required"`
+	Payload   []byte   `json:"input"    gencodec:"required"`
 
 	// Signature values
 	V *big.Int `json:"v" gencodec:"required"`
@@ -192,10 +268,76 @@ type txdata struct {
 	Hash *Hash `json:"hash" rlp:"-"`
 }
 
+// accessListPayload is the RLP payload of an AccessListTxType envelope,
+// i.e. everything after the leading type byte.
+type accessListPayload struct {
+	ChainID      *big.Int
+	AccountNonce uint64
+	Price        *big.Int
+	GasLimit     uint64
+	Recipient    *Address `rlp:"nil"`
+	Amount       *big.Int
+	Payload      []byte
+	AccessList   AccessList
+	V, R, S      *big.Int
+}
+
+// dynamicFeePayload is the RLP payload of a DynamicFeeTxType envelope,
+// i.e. everything after the leading type byte.
+type dynamicFeePayload struct {
+	ChainID      *big.Int
+	AccountNonce uint64
+	GasTipCap    *big.Int
+	GasFeeCap    *big.Int
+	GasLimit     uint64
+	Recipient    *Address `rlp:"nil"`
+	Amount       *big.Int
+	Payload      []byte
+	AccessList   AccessList
+	V, R, S      *big.Int
+}
+
 func NewTransactions(nonce uint64, to Address, amount *big.Int, gasLimit uint64, gasPrice *big.Int, data []byte) *Transactions {
 	return newTransactions(nonce, &to, amount, gasLimit, gasPrice, data)
 }
 
+// NewAccessListTx creates an EIP-2930 access-list transaction.
+func NewAccessListTx(chainID *big.Int, nonce uint64, to *Address, amount *big.Int, gasLimit uint64, gasPrice *big.Int, data []byte, accessList AccessList) *Transactions {
+	tx := newTransactions(nonce, to, amount, gasLimit, gasPrice, data)
+	tx.data.Type = AccessListTxType
+	tx.data.ChainID = new(big.Int)
+	if chainID != nil {
+		tx.data.ChainID.Set(chainID)
+	}
+	tx.data.AccessList = accessList
+	return tx
+}
+
+// NewDynamicFeeTx creates an EIP-1559 dynamic-fee transaction.
+func NewDynamicFeeTx(chainID *big.Int, nonce uint64, to *Address, amount *big.Int, gasLimit uint64, gasTipCap, gasFeeCap *big.Int, data []byte, accessList AccessList) *Transactions {
+	tx := newTransactions(nonce, to, amount, gasLimit, nil, data)
+	tx.data.Type = DynamicFeeTxType
+	tx.data.ChainID = new(big.Int)
+	if chainID != nil {
+		tx.data.ChainID.Set(chainID)
+	}
+	tx.data.GasTipCap = new(big.Int)
+	if gasTipCap != nil {
+		tx.data.GasTipCap.Set(gasTipCap)
+	}
+	tx.data.GasFeeCap = new(big.Int)
+	if gasFeeCap != nil {
+		tx.data.GasFeeCap.Set(gasFeeCap)
+	}
+	tx.data.AccessList = accessList
+	return tx
+}
+
+// Type returns the EIP-2718 envelope type of the transaction.
+func (tx *Transactions) Type() TxType {
+	return tx.data.Type
+}
+
 // CopyBytes returns an exact copy of the provided bytes.
 func CopyBytes(b []byte) (copiedBytes []byte) {
 	if b == nil {
@@ -229,10 +371,147 @@ func newTransactions(nonce uint64, to *Address, amount *big.Int, gasLimit uint64
 		d.Price.Set(gasPrice)
 	}
 
-	return &Transactions{data: d}
+	return &Transactions{data: d, time: time.Now()}
 }
 
-// EncodeRLP implements rlp.Encoder
+// EncodeRLP implements rlp.Encoder. Legacy transactions are encoded as a
+// plain RLP list, as always. Typed transactions are encoded as their
+// EIP-2718 envelope (type byte || RLP payload) wrapped as a single RLP
+// byte-string, which is how they must appear when embedded in an RLP list
+// such as a block's transaction list.
 func (tx *Transactions) EncodeRLP(w io.Writer) error {
-	return rlp.Encode(w, &tx.data)
-}
\ No newline at end of file
+	if tx.data.Type == LegacyTxType {
+		return rlp.Encode(w, &tx.data)
+	}
+	buf := new(bytes.Buffer)
+	if err := tx.encodeTyped(buf); err != nil {
+		return err
+	}
+	return rlp.Encode(w, buf.Bytes())
+}
+
+// encodeTyped writes the EIP-2718 envelope (type byte followed by the RLP
+// payload) for a typed transaction to w.
+func (tx *Transactions) encodeTyped(w *bytes.Buffer) error {
+	switch tx.data.Type {
+	case AccessListTxType:
+		w.WriteByte(byte(AccessListTxType))
+		return rlp.Encode(w, &accessListPayload{
+			ChainID:      tx.data.ChainID,
+			AccountNonce: tx.data.AccountNonce,
+			Price:        tx.data.Price,
+			GasLimit:     tx.data.GasLimit,
+			Recipient:    tx.data.Recipient,
+			Amount:       tx.data.Amount,
+			Payload:      tx.data.Payload,
+			AccessList:   tx.data.AccessList,
+			V:            tx.data.V,
+			R:            tx.data.R,
+			S:            tx.data.S,
+		})
+	case DynamicFeeTxType:
+		w.WriteByte(byte(DynamicFeeTxType))
+		return rlp.Encode(w, &dynamicFeePayload{
+			ChainID:      tx.data.ChainID,
+			AccountNonce: tx.data.AccountNonce,
+			GasTipCap:    tx.data.GasTipCap,
+			GasFeeCap:    tx.data.GasFeeCap,
+			GasLimit:     tx.data.GasLimit,
+			Recipient:    tx.data.Recipient,
+			Amount:       tx.data.Amount,
+			Payload:      tx.data.Payload,
+			AccessList:   tx.data.AccessList,
+			V:            tx.data.V,
+			R:            tx.data.R,
+			S:            tx.data.S,
+		})
+	default:
+		return ErrTxTypeNotSupported
+	}
+}
+
+// MarshalBinary returns the canonical encoding of the transaction: plain
+// RLP for legacy transactions, or the EIP-2718 envelope (type byte || RLP
+// payload) for typed ones. This is the "raw" transaction format used by
+// eth_sendRawTransaction and p2p transaction propagation.
+func (tx *Transactions) MarshalBinary() ([]byte, error) {
+	if tx.data.Type == LegacyTxType {
+		return rlp.EncodeToBytes(&tx.data)
+	}
+	buf := new(bytes.Buffer)
+	if err := tx.encodeTyped(buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// UnmarshalBinary decodes the canonical encoding of a transaction produced
+// by MarshalBinary.
+func (tx *Transactions) UnmarshalBinary(b []byte) error {
+	if len(b) == 0 {
+		return ErrEmptyTypedTx
+	}
+	tx.time = time.Now()
+	// A legacy transaction is always RLP-encoded as a list, whose first
+	// byte is >= 0xc0. Anything below that is an EIP-2718 type byte.
+	if b[0] >= 0xc0 {
+		var data txdata
+		if err := rlp.DecodeBytes(b, &data); err != nil {
+			return err
+		}
+		data.Type = LegacyTxType
+		tx.data = data
+		return nil
+	}
+	return tx.decodeTyped(TxType(b[0]), b[1:])
+}
+
+// decodeTyped decodes the RLP payload of a typed transaction envelope into
+// tx.data.
+func (tx *Transactions) decodeTyped(txType TxType, payload []byte) error {
+	switch txType {
+	case AccessListTxType:
+		var p accessListPayload
+		if err := rlp.DecodeBytes(payload, &p); err != nil {
+			return err
+		}
+		tx.data = txdata{
+			Type:         AccessListTxType,
+			ChainID:      p.ChainID,
+			AccountNonce: p.AccountNonce,
+			Price:        p.Price,
+			GasLimit:     p.GasLimit,
+			Recipient:    p.Recipient,
+			Amount:       p.Amount,
+			Payload:      p.Payload,
+			AccessList:   p.AccessList,
+			V:            p.V,
+			R:            p.R,
+			S:            p.S,
+		}
+		return nil
+	case DynamicFeeTxType:
+		var p dynamicFeePayload
+		if err := rlp.DecodeBytes(payload, &p); err != nil {
+			return err
+		}
+		tx.data = txdata{
+			Type:         DynamicFeeTxType,
+			ChainID:      p.ChainID,
+			AccountNonce: p.AccountNonce,
+			GasTipCap:    p.GasTipCap,
+			GasFeeCap:    p.GasFeeCap,
+			GasLimit:     p.GasLimit,
+			Recipient:    p.Recipient,
+			Amount:       p.Amount,
+			Payload:      p.Payload,
+			AccessList:   p.AccessList,
+			V:            p.V,
+			R:            p.R,
+			S:            p.S,
+		}
+		return nil
+	default:
+		return ErrTxTypeNotSupported
+	}
+}